@@ -0,0 +1,318 @@
+// Copyright (c) 2012, James Helferty. All rights reserved.
+// Use of this source code is governed by a Clear BSD License
+// that can be found in the LICENSE file.
+
+package gla
+
+import "encoding/binary"
+import "fmt"
+import "image"
+import "image/color"
+import "io"
+import "io/ioutil"
+import "math/bits"
+import "github.com/spate/glimage"
+
+// RawCompressed is a minimal CompressedImage backed by a flat byte buffer.
+// LoadDDS and LoadKTX use it for the block-compressed formats (RGTC, BPTC)
+// that don't have a dedicated glimage type; it's a stand-in for whatever
+// concrete type glimage eventually grows for those formats.
+type RawCompressed struct {
+	Pix    []uint8
+	Stride int
+	Rect   image.Rectangle
+	Dim    int // block dimension, e.g. 4 for a 4x4 block
+	Size   int // bytes per block
+	Format GLenum
+}
+
+func (c *RawCompressed) ColorModel() color.Model  { return color.RGBAModel }
+func (c *RawCompressed) Bounds() image.Rectangle  { return c.Rect }
+func (c *RawCompressed) At(x, y int) color.Color  { return color.RGBA{} }
+func (c *RawCompressed) CompressedPix() []uint8   { return c.Pix }
+func (c *RawCompressed) CompressedStride() int    { return c.Stride }
+func (c *RawCompressed) BlockDim() int            { return c.Dim }
+func (c *RawCompressed) BlockSize() int           { return c.Size }
+func (c *RawCompressed) CompressedFormat() GLenum { return c.Format }
+
+const (
+	ddsMagic = 0x20534444 // "DDS "
+
+	fourccDXT1 = 0x31545844 // "DXT1"
+	fourccDXT3 = 0x33545844 // "DXT3"
+	fourccDXT5 = 0x35545844 // "DXT5"
+	fourccATI1 = 0x31495441 // "ATI1", RGTC1
+	fourccATI2 = 0x32495441 // "ATI2", RGTC2
+	fourccDX10 = 0x30315844 // "DX10", extended header follows
+
+	dxgiFormatBC4Unorm = 80
+	dxgiFormatBC5Unorm = 83
+	dxgiFormatBC7Unorm = 98
+)
+
+type ddsPixelFormat struct {
+	Size        uint32
+	Flags       uint32
+	FourCC      uint32
+	RGBBitCount uint32
+	RBitMask    uint32
+	GBitMask    uint32
+	BBitMask    uint32
+	ABitMask    uint32
+}
+
+type ddsHeader struct {
+	Size           uint32
+	Flags          uint32
+	Height         uint32
+	Width          uint32
+	PitchOrLinSize uint32
+	Depth          uint32
+	MipMapCount    uint32
+	Reserved1      [11]uint32
+	PixelFormat    ddsPixelFormat
+	Caps           uint32
+	Caps2          uint32
+	Caps3          uint32
+	Caps4          uint32
+	Reserved2      uint32
+}
+
+type ddsHeaderDX10 struct {
+	DXGIFormat        uint32
+	ResourceDimension uint32
+	MiscFlag          uint32
+	ArraySize         uint32
+	MiscFlags2        uint32
+}
+
+// blockInfo returns the block dimension, bytes per block and GL compressed
+// format for one of the fourCC/DXGI_FORMAT codes LoadDDS recognizes. LoadKTX
+// uses ktxBlockInfo instead.
+func blockInfo(fourCC uint32, dxgiFormat uint32) (dim, size int, format GLenum, err error) {
+	switch fourCC {
+	case fourccDXT1:
+		return 4, 8, COMPRESSED_RGBA_S3TC_DXT1, nil
+	case fourccDXT3:
+		return 4, 16, COMPRESSED_RGBA_S3TC_DXT3, nil
+	case fourccDXT5:
+		return 4, 16, COMPRESSED_RGBA_S3TC_DXT5, nil
+	case fourccATI1:
+		return 4, 8, COMPRESSED_RED_RGTC1, nil
+	case fourccATI2:
+		return 4, 16, COMPRESSED_RG_RGTC2, nil
+	case fourccDX10:
+		switch dxgiFormat {
+		case dxgiFormatBC4Unorm:
+			return 4, 8, COMPRESSED_RED_RGTC1, nil
+		case dxgiFormatBC5Unorm:
+			return 4, 16, COMPRESSED_RG_RGTC2, nil
+		case dxgiFormatBC7Unorm:
+			return 4, 16, COMPRESSED_RGBA_BPTC_UNORM, nil
+		}
+	}
+	return 0, 0, 0, fmt.Errorf("gla: unrecognized DDS fourCC/DXGI_FORMAT")
+}
+
+// newCompressedImage builds the image type getCompressedImageInfo already
+// knows about for format, falling back to RawCompressed for the formats
+// that don't have one.
+func newCompressedImage(format GLenum, dim, size int, rect image.Rectangle, stride int, pix []uint8) image.Image {
+	switch format {
+	case COMPRESSED_RGBA_S3TC_DXT1:
+		return &glimage.Dxt1{Pix: pix, Stride: stride, Rect: rect}
+	case COMPRESSED_RGBA_S3TC_DXT3:
+		return &glimage.Dxt3{Pix: pix, Stride: stride, Rect: rect}
+	case COMPRESSED_RGBA_S3TC_DXT5:
+		return &glimage.Dxt5{Pix: pix, Stride: stride, Rect: rect}
+	default:
+		return &RawCompressed{Pix: pix, Stride: stride, Rect: rect, Dim: dim, Size: size, Format: format}
+	}
+}
+
+// LoadDDS parses a DDS container from r and returns its base (level 0)
+// image, as one of the image types CompressedTexImage2DFromImage accepts.
+// It recognizes the DXT1/3/5, RGTC (ATI1/ATI2) and, via the DX10 extended
+// header, BPTC (BC7) fourCC codes. Any mipmap levels beyond the base are
+// discarded; use LoadKTX for access to the full mip chain.
+func LoadDDS(r io.Reader) (image.Image, error) {
+	var magic uint32
+	if err := binary.Read(r, binary.LittleEndian, &magic); err != nil {
+		return nil, err
+	}
+	if magic != ddsMagic {
+		return nil, fmt.Errorf("gla: not a DDS file")
+	}
+
+	var hdr ddsHeader
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return nil, err
+	}
+
+	dxgiFormat := uint32(0)
+	if hdr.PixelFormat.FourCC == fourccDX10 {
+		var dx10 ddsHeaderDX10
+		if err := binary.Read(r, binary.LittleEndian, &dx10); err != nil {
+			return nil, err
+		}
+		dxgiFormat = dx10.DXGIFormat
+	}
+
+	dim, size, format, err := blockInfo(hdr.PixelFormat.FourCC, dxgiFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	w, h := int(hdr.Width), int(hdr.Height)
+	rect := image.Rect(0, 0, w, h)
+	stride := (w + dim - 1) / dim * size
+
+	pix, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	blockRows := (h + dim - 1) / dim
+	need := blockRows * stride
+	if len(pix) < need {
+		return nil, fmt.Errorf("gla: truncated DDS data")
+	}
+
+	return newCompressedImage(format, dim, size, rect, stride, pix[:need]), nil
+}
+
+const ktxIdentifier = "\xABKTX 11\xBB\r\n\x1A\n"
+
+type ktxHeader struct {
+	Endianness            uint32
+	GLType                uint32
+	GLTypeSize            uint32
+	GLFormat              uint32
+	GLInternalFormat      uint32
+	GLBaseInternalFormat  uint32
+	PixelWidth            uint32
+	PixelHeight           uint32
+	PixelDepth            uint32
+	NumberOfArrayElements uint32
+	NumberOfFaces         uint32
+	NumberOfMipmapLevels  uint32
+	BytesOfKeyValueData   uint32
+}
+
+// ktxBlockInfo maps the handful of GLInternalFormat values LoadKTX
+// recognizes to block metrics, reusing the same GL format constants as the
+// DDS loader.
+func ktxBlockInfo(internalformat uint32) (dim, size int, format GLenum, err error) {
+	switch GLenum(internalformat) {
+	case COMPRESSED_RGBA_S3TC_DXT1:
+		return 4, 8, COMPRESSED_RGBA_S3TC_DXT1, nil
+	case COMPRESSED_RGBA_S3TC_DXT3:
+		return 4, 16, COMPRESSED_RGBA_S3TC_DXT3, nil
+	case COMPRESSED_RGBA_S3TC_DXT5:
+		return 4, 16, COMPRESSED_RGBA_S3TC_DXT5, nil
+	case COMPRESSED_RED_RGTC1:
+		return 4, 8, COMPRESSED_RED_RGTC1, nil
+	case COMPRESSED_RG_RGTC2:
+		return 4, 16, COMPRESSED_RG_RGTC2, nil
+	case COMPRESSED_RGBA_BPTC_UNORM, COMPRESSED_SRGB_ALPHA_BPTC_UNORM:
+		return 4, 16, GLenum(internalformat), nil
+	}
+	return 0, 0, 0, fmt.Errorf("gla: unrecognized KTX GLInternalFormat")
+}
+
+// LoadKTX parses a KTX container from r and returns its base (level 0)
+// image plus the remaining levels of its mipmap chain, in the same
+// CompressedTexImage2DFromImage-compatible representation LoadDDS uses.
+// Only the common case of one array element and one face is supported.
+func LoadKTX(r io.Reader) (image.Image, []image.Image, error) {
+	ident := make([]byte, len(ktxIdentifier))
+	if _, err := io.ReadFull(r, ident); err != nil {
+		return nil, nil, err
+	}
+	if string(ident) != ktxIdentifier {
+		return nil, nil, fmt.Errorf("gla: not a KTX file")
+	}
+
+	var hdr ktxHeader
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return nil, nil, err
+	}
+	if hdr.Endianness != 0x04030201 {
+		return nil, nil, fmt.Errorf("gla: big-endian KTX files are not supported")
+	}
+	if hdr.NumberOfArrayElements > 1 || hdr.NumberOfFaces > 1 {
+		return nil, nil, fmt.Errorf("gla: KTX array textures and cubemaps are not supported")
+	}
+
+	dim, size, format, err := ktxBlockInfo(hdr.GLInternalFormat)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if hdr.BytesOfKeyValueData > 0 {
+		if _, err := io.CopyN(ioutil.Discard, r, int64(hdr.BytesOfKeyValueData)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	levels := int(hdr.NumberOfMipmapLevels)
+	if levels == 0 {
+		levels = 1
+	}
+
+	w, h := int(hdr.PixelWidth), int(hdr.PixelHeight)
+	if w < 1 || h < 1 {
+		return nil, nil, fmt.Errorf("gla: KTX file has invalid dimensions %dx%d", w, h)
+	}
+
+	// a NumberOfMipmapLevels beyond what the base image can actually
+	// provide (full chain down to 1x1) means a corrupt or hostile header;
+	// reject it here rather than trusting it as an allocation size below.
+	maxDim := w
+	if h > maxDim {
+		maxDim = h
+	}
+	if maxLevels := bits.Len(uint(maxDim)); levels > maxLevels {
+		return nil, nil, fmt.Errorf("gla: KTX header claims %d mip levels, but a %dx%d base image can have at most %d", levels, w, h, maxLevels)
+	}
+
+	images := make([]image.Image, 0, levels)
+
+	for level := 0; level < levels; level++ {
+		var imageSize uint32
+		if err := binary.Read(r, binary.LittleEndian, &imageSize); err != nil {
+			return nil, nil, err
+		}
+
+		rect := image.Rect(0, 0, w, h)
+		stride := (w + dim - 1) / dim * size
+		blockRows := (h + dim - 1) / dim
+		need := blockRows * stride
+		if int(imageSize) != need {
+			return nil, nil, fmt.Errorf("gla: KTX level %d declares imageSize %d, expected %d for a %dx%d block-compressed image", level, imageSize, need, w, h)
+		}
+
+		pix := make([]uint8, imageSize)
+		if _, err := io.ReadFull(r, pix); err != nil {
+			return nil, nil, err
+		}
+
+		images = append(images, newCompressedImage(format, dim, size, rect, stride, pix))
+
+		// mip data is padded to a 4-byte boundary
+		if pad := (4 - int(imageSize)%4) % 4; pad > 0 {
+			if _, err := io.CopyN(ioutil.Discard, r, int64(pad)); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if w > 1 {
+			w /= 2
+		}
+		if h > 1 {
+			h /= 2
+		}
+	}
+
+	return images[0], images[1:], nil
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2012, James Helferty. All rights reserved.
+// Use of this source code is governed by a Clear BSD License
+// that can be found in the LICENSE file.
+
+package gla
+
+// #cgo darwin LDFLAGS: -framework OpenGL
+// #cgo darwin pkg-config: glew
+// #cgo windows LDFLAGS: -lglew32 -lopengl32
+// #cgo linux LDFLAGS: -lGLEW -lGL
+//
+// #include <stdlib.h>
+//
+// #ifdef __APPLE__
+// # include "glew.h"
+// #else
+// # include <GL/glew.h>
+// #endif
+//
+// #undef GLEW_GET_FUN
+// #define GLEW_GET_FUN(x) (*x)
+import "C"
+import "unsafe"
+import "reflect"
+import "github.com/banthar/gl"
+
+// AttribChannel identifies one of the fixed-function vertex pointer slots
+// (as bound by VertexSlice, NormalSlice, ColorSlice and TexCoordSlice) that
+// an AttribBinding can feed, as an alternative to a generic vertex attribute
+// index.
+type AttribChannel int
+
+const (
+	ChannelNone AttribChannel = iota
+	ChannelVertex
+	ChannelNormal
+	ChannelColor
+	ChannelTexCoord
+)
+
+// AttribBinding describes one vertex attribute pulled out of the struct
+// passed to NewDrawSet as vertexDummy. FieldIndex selects the struct field,
+// the same way dummy_index does for VertexAttribSlice. Set Channel to bind
+// one of the fixed-function pointers, or leave it as ChannelNone and set
+// Index to bind a generic vertex attribute via glVertexAttribPointer.
+type AttribBinding struct {
+	FieldIndex int
+	Index      uint
+	Channel    AttribChannel
+	Normalized bool
+}
+
+// DrawSet wraps a vertex array object together with the vertex/index buffers
+// bound into it, so that the layout described by a slice of AttribBinding
+// only needs to be set up once, in NewDrawSet, instead of being reissued
+// with VertexAttribSlice and friends before every draw call.
+type DrawSet struct {
+	vao       C.GLuint
+	vbo       C.GLuint
+	ibo       C.GLuint
+	attribs   []AttribBinding
+	indexType GLenum
+}
+
+// NewDrawSet creates a DrawSet whose vertex layout is described by attribs,
+// using vertexDummy to infer each attribute's GL type, element count and
+// offset/stride the same way VertexAttribSlice does. It allocates a vertex
+// array object and a vertex buffer object, and records the pointer state for
+// every entry in attribs against that VAO so it doesn't need to be reissued
+// before each draw; fill the vertex buffer with SetVertexData before
+// drawing.
+//
+// Additional state modified: currently bound VERTEX_ARRAY_BINDING and
+// ARRAY_BUFFER are left unbound on return
+func NewDrawSet(vertexDummy interface{}, attribs []AttribBinding) (*DrawSet, error) {
+	ds := &DrawSet{attribs: attribs}
+
+	var vao, vbo C.GLuint
+	C.glGenVertexArrays(1, &vao)
+	C.glGenBuffers(1, &vbo)
+	ds.vao, ds.vbo = vao, vbo
+
+	C.glBindVertexArray(ds.vao)
+	C.glBindBuffer(C.GLenum(gl.ARRAY_BUFFER), ds.vbo)
+
+	for _, a := range attribs {
+		data, err := sliceAttrib(vertexDummy, a.FieldIndex)
+		if err != nil {
+			C.glBindVertexArray(0)
+			ds.Close()
+			return nil, err
+		}
+
+		switch a.Channel {
+		case ChannelVertex:
+			C.glEnableClientState(C.GLenum(gl.VERTEX_ARRAY))
+			C.glVertexPointer(C.GLint(data.Elements), C.GLenum(data.Gltype), C.GLsizei(data.Stride), unsafe.Pointer(data.Offset))
+		case ChannelNormal:
+			C.glEnableClientState(C.GLenum(gl.NORMAL_ARRAY))
+			C.glNormalPointer(C.GLenum(data.Gltype), C.GLsizei(data.Stride), unsafe.Pointer(data.Offset))
+		case ChannelColor:
+			C.glEnableClientState(C.GLenum(gl.COLOR_ARRAY))
+			C.glColorPointer(C.GLint(data.Elements), C.GLenum(data.Gltype), C.GLsizei(data.Stride), unsafe.Pointer(data.Offset))
+		case ChannelTexCoord:
+			C.glEnableClientState(C.GLenum(gl.TEXTURE_COORD_ARRAY))
+			C.glTexCoordPointer(C.GLint(data.Elements), C.GLenum(data.Gltype), C.GLsizei(data.Stride), unsafe.Pointer(data.Offset))
+		default:
+			C.glEnableVertexAttribArray(C.GLuint(a.Index))
+			C.glVertexAttribPointer(C.GLuint(a.Index), C.GLint(data.Elements), C.GLenum(data.Gltype), glBool(a.Normalized), C.GLsizei(data.Stride), unsafe.Pointer(data.Offset))
+		}
+	}
+
+	C.glBindVertexArray(0)
+	C.glBindBuffer(C.GLenum(gl.ARRAY_BUFFER), 0)
+
+	return ds, nil
+}
+
+// SetVertexData uses BufferData to (re)upload slice into the DrawSet's
+// vertex buffer, under the usage hint usage.
+//
+// Additional state modified: ARRAY_BUFFER is left bound to the DrawSet's
+// vertex buffer
+func (ds *DrawSet) SetVertexData(slice interface{}, usage GLenum) error {
+	C.glBindBuffer(C.GLenum(gl.ARRAY_BUFFER), ds.vbo)
+	return BufferData(GLenum(gl.ARRAY_BUFFER), slice, usage)
+}
+
+// SetIndexData uses BufferData to (re)upload slice into the DrawSet's index
+// buffer, allocating one on first use, under the usage hint usage. The
+// element type of slice (one of the integer types accepted by
+// sliceFieldToGL) determines the type DrawElements passes to
+// glDrawElements.
+//
+// Additional state modified: VERTEX_ARRAY_BINDING is left unbound on return
+func (ds *DrawSet) SetIndexData(slice interface{}, usage GLenum) error {
+	indexType, err := sliceFieldToGL(reflect.TypeOf(slice).Elem())
+	if err != nil {
+		return err
+	}
+
+	if ds.ibo == 0 {
+		var ibo C.GLuint
+		C.glGenBuffers(1, &ibo)
+		ds.ibo = ibo
+	}
+
+	ds.indexType = indexType
+
+	C.glBindVertexArray(ds.vao)
+	C.glBindBuffer(C.GLenum(gl.ELEMENT_ARRAY_BUFFER), ds.ibo)
+	err = BufferData(GLenum(gl.ELEMENT_ARRAY_BUFFER), slice, usage)
+	C.glBindVertexArray(0)
+	return err
+}
+
+// Bind binds this DrawSet's vertex array object, making its vertex layout
+// and buffer bindings current.
+func (ds *DrawSet) Bind() {
+	C.glBindVertexArray(ds.vao)
+}
+
+// Unbind unbinds the currently bound vertex array object.
+func (ds *DrawSet) Unbind() {
+	C.glBindVertexArray(0)
+}
+
+// Draw binds the DrawSet and issues glDrawArrays.
+func (ds *DrawSet) Draw(mode GLenum, first, count int) {
+	ds.Bind()
+	C.glDrawArrays(C.GLenum(mode), C.GLint(first), C.GLsizei(count))
+}
+
+// DrawElements binds the DrawSet and issues glDrawElements over the index
+// buffer set by SetIndexData.
+//
+// Precondition: SetIndexData has been called at least once
+func (ds *DrawSet) DrawElements(mode GLenum, count int) {
+	ds.Bind()
+	C.glDrawElements(C.GLenum(mode), C.GLsizei(count), C.GLenum(ds.indexType), nil)
+}
+
+// Close deletes the GL objects (vertex array, vertex buffer, index buffer)
+// owned by this DrawSet.
+func (ds *DrawSet) Close() error {
+	if ds.vao != 0 {
+		vao := ds.vao
+		C.glDeleteVertexArrays(1, &vao)
+		ds.vao = 0
+	}
+	if ds.vbo != 0 {
+		vbo := ds.vbo
+		C.glDeleteBuffers(1, &vbo)
+		ds.vbo = 0
+	}
+	if ds.ibo != 0 {
+		ibo := ds.ibo
+		C.glDeleteBuffers(1, &ibo)
+		ds.ibo = 0
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2012, James Helferty. All rights reserved.
+// Use of this source code is governed by a Clear BSD License
+// that can be found in the LICENSE file.
+
+package gla
+
+// #cgo darwin LDFLAGS: -framework OpenGL
+// #cgo darwin pkg-config: glew
+// #cgo windows LDFLAGS: -lglew32 -lopengl32
+// #cgo linux LDFLAGS: -lGLEW -lGL
+//
+// #include <stdlib.h>
+//
+// #ifdef __APPLE__
+// # include "glew.h"
+// #else
+// # include <GL/glew.h>
+// #endif
+//
+// #undef GLEW_GET_FUN
+// #define GLEW_GET_FUN(x) (*x)
+import "C"
+import "fmt"
+import "image"
+import "github.com/banthar/gl"
+
+// The following are missing from github.com/banthar/gl
+const (
+	FRAMEBUFFER                               = 0x8D40
+	READ_FRAMEBUFFER                          = 0x8CA8
+	DRAW_FRAMEBUFFER                          = 0x8CA9
+	RENDERBUFFER                              = 0x8D41
+	COLOR_ATTACHMENT0                         = 0x8CE0
+	DEPTH_ATTACHMENT                          = 0x8D00
+	STENCIL_ATTACHMENT                        = 0x8D20
+	DEPTH_STENCIL_ATTACHMENT                  = 0x821A
+	FRAMEBUFFER_COMPLETE                      = 0x8CD5
+	FRAMEBUFFER_INCOMPLETE_ATTACHMENT         = 0x8CD6
+	FRAMEBUFFER_INCOMPLETE_MISSING_ATTACHMENT = 0x8CD7
+	FRAMEBUFFER_INCOMPLETE_DRAW_BUFFER        = 0x8CDB
+	FRAMEBUFFER_INCOMPLETE_READ_BUFFER        = 0x8CDC
+	FRAMEBUFFER_UNSUPPORTED                   = 0x8CDD
+	FRAMEBUFFER_INCOMPLETE_MULTISAMPLE        = 0x8D56
+	FRAMEBUFFER_UNDEFINED                     = 0x8219
+)
+
+// FramebufferError is returned by Framebuffer.CheckStatus when the
+// framebuffer isn't complete, wrapping the GL_FRAMEBUFFER_*_COMPLETE status
+// that glCheckFramebufferStatus reported.
+type FramebufferError struct {
+	Status GLenum
+}
+
+func (e FramebufferError) Error() string {
+	switch e.Status {
+	case FRAMEBUFFER_INCOMPLETE_ATTACHMENT:
+		return "gla: framebuffer incomplete attachment"
+	case FRAMEBUFFER_INCOMPLETE_MISSING_ATTACHMENT:
+		return "gla: framebuffer missing attachment"
+	case FRAMEBUFFER_INCOMPLETE_DRAW_BUFFER:
+		return "gla: framebuffer incomplete draw buffer"
+	case FRAMEBUFFER_INCOMPLETE_READ_BUFFER:
+		return "gla: framebuffer incomplete read buffer"
+	case FRAMEBUFFER_UNSUPPORTED:
+		return "gla: framebuffer unsupported"
+	case FRAMEBUFFER_INCOMPLETE_MULTISAMPLE:
+		return "gla: framebuffer incomplete multisample"
+	case FRAMEBUFFER_UNDEFINED:
+		return "gla: framebuffer undefined"
+	default:
+		return fmt.Sprintf("gla: framebuffer incomplete (status 0x%x)", uint32(e.Status))
+	}
+}
+
+// Framebuffer wraps a framebuffer object, mirroring the texture helpers
+// in gla.go for the render-to-texture case: create one, attach textures and/or
+// renderbuffers to it, and check it's complete before rendering into it.
+type Framebuffer struct {
+	fbo C.GLuint
+}
+
+// NewFramebuffer creates a new, empty framebuffer object.
+func NewFramebuffer() *Framebuffer {
+	var fbo C.GLuint
+	C.glGenFramebuffers(1, &fbo)
+	return &Framebuffer{fbo: fbo}
+}
+
+// Bind uses glBindFramebuffer to bind this framebuffer to target (one of
+// FRAMEBUFFER, READ_FRAMEBUFFER or DRAW_FRAMEBUFFER).
+func (fb *Framebuffer) Bind(target GLenum) {
+	C.glBindFramebuffer(C.GLenum(target), fb.fbo)
+}
+
+// AttachTexture2D uses glFramebufferTexture2D to attach level level of tex
+// to attachment (e.g. COLOR_ATTACHMENT0, DEPTH_ATTACHMENT) of this
+// framebuffer.
+//
+// Precondition: this framebuffer bound to FRAMEBUFFER (or DRAW_FRAMEBUFFER)
+func (fb *Framebuffer) AttachTexture2D(attachment GLenum, tex gl.Texture, level int) {
+	C.glFramebufferTexture2D(C.GLenum(FRAMEBUFFER), C.GLenum(attachment), C.GLenum(gl.TEXTURE_2D), C.GLuint(tex), C.GLint(level))
+}
+
+// AttachRenderbuffer uses glFramebufferRenderbuffer to attach rb to
+// attachment (e.g. COLOR_ATTACHMENT0, DEPTH_STENCIL_ATTACHMENT) of this
+// framebuffer.
+//
+// Precondition: this framebuffer bound to FRAMEBUFFER (or DRAW_FRAMEBUFFER)
+func (fb *Framebuffer) AttachRenderbuffer(attachment GLenum, rb *Renderbuffer) {
+	C.glFramebufferRenderbuffer(C.GLenum(FRAMEBUFFER), C.GLenum(attachment), C.GLenum(RENDERBUFFER), rb.rbo)
+}
+
+// CheckStatus uses glCheckFramebufferStatus to verify this framebuffer is
+// complete, returning a FramebufferError describing the failure if not.
+//
+// Precondition: this framebuffer bound to FRAMEBUFFER (or DRAW_FRAMEBUFFER)
+func (fb *Framebuffer) CheckStatus() error {
+	status := GLenum(C.glCheckFramebufferStatus(C.GLenum(FRAMEBUFFER)))
+	if status != FRAMEBUFFER_COMPLETE {
+		return FramebufferError{Status: status}
+	}
+	return nil
+}
+
+// Close deletes the GL framebuffer object owned by fb. It does not delete
+// any texture or renderbuffer attached to it.
+func (fb *Framebuffer) Close() error {
+	if fb.fbo != 0 {
+		fbo := fb.fbo
+		C.glDeleteFramebuffers(1, &fbo)
+		fb.fbo = 0
+	}
+	return nil
+}
+
+// Renderbuffer wraps a renderbuffer object, for use as a Framebuffer
+// attachment that doesn't need to be sampled as a texture.
+type Renderbuffer struct {
+	rbo C.GLuint
+}
+
+// NewRenderbufferStorage creates a renderbuffer and uses
+// glRenderbufferStorage to allocate width x height storage of internalformat
+// for it. If samples is greater than zero, glRenderbufferStorageMultisample
+// is used instead to request a multisampled renderbuffer.
+//
+// Additional state modified: RENDERBUFFER is left unbound on return
+func NewRenderbufferStorage(internalformat GLenum, width, height, samples int) *Renderbuffer {
+	var rbo C.GLuint
+	C.glGenRenderbuffers(1, &rbo)
+
+	C.glBindRenderbuffer(C.GLenum(RENDERBUFFER), rbo)
+	if samples > 0 {
+		C.glRenderbufferStorageMultisample(C.GLenum(RENDERBUFFER), C.GLsizei(samples), C.GLenum(internalformat), C.GLsizei(width), C.GLsizei(height))
+	} else {
+		C.glRenderbufferStorage(C.GLenum(RENDERBUFFER), C.GLenum(internalformat), C.GLsizei(width), C.GLsizei(height))
+	}
+	C.glBindRenderbuffer(C.GLenum(RENDERBUFFER), 0)
+
+	return &Renderbuffer{rbo: rbo}
+}
+
+// Close deletes the GL renderbuffer object owned by rb.
+func (rb *Renderbuffer) Close() error {
+	if rb.rbo != 0 {
+		rbo := rb.rbo
+		C.glDeleteRenderbuffers(1, &rbo)
+		rb.rbo = 0
+	}
+	return nil
+}
+
+// BlitFramebuffer uses glBlitFramebuffer to copy the rectangle src of the
+// framebuffer bound to READ_FRAMEBUFFER into the rectangle dst of the
+// framebuffer bound to DRAW_FRAMEBUFFER, resolving multisampling and
+// scaling (per filter) as needed.
+//
+// Precondition: framebuffers bound to READ_FRAMEBUFFER and DRAW_FRAMEBUFFER
+func BlitFramebuffer(src, dst image.Rectangle, mask GLbitfield, filter GLenum) {
+	C.glBlitFramebuffer(
+		C.GLint(src.Min.X), C.GLint(src.Min.Y), C.GLint(src.Max.X), C.GLint(src.Max.Y),
+		C.GLint(dst.Min.X), C.GLint(dst.Min.Y), C.GLint(dst.Max.X), C.GLint(dst.Max.Y),
+		C.GLbitfield(mask), C.GLenum(filter))
+}
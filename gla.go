@@ -83,30 +83,41 @@ type imageInfo struct {
 
 // The following are missing from github.com/banthar/gl
 const (
-	COMPRESSED_RGB_S3TC_DXT1        = 0x83F0
-	COMPRESSED_RGBA_S3TC_DXT1       = 0x83F1
-	COMPRESSED_RGBA_S3TC_DXT3       = 0x83F2
-	COMPRESSED_RGBA_S3TC_DXT5       = 0x83F3
-	COMPRESSED_SRGB_S3TC_DXT1       = 0x8C4C
-	COMPRESSED_SRGB_ALPHA_S3TC_DXT1 = 0x8C4D
-	COMPRESSED_SRGB_ALPHA_S3TC_DXT3 = 0x8C4E
-	COMPRESSED_SRGB_ALPHA_S3TC_DXT5 = 0x8C4F
-	UNSIGNED_BYTE_3_3_2             = 0x8032
-	UNSIGNED_SHORT_4_4_4_4          = 0x8033
-	UNSIGNED_SHORT_5_5_5_1          = 0x8034
-	UNSIGNED_INT_8_8_8_8            = 0x8035
-	UNSIGNED_INT_10_10_10_2         = 0x8036
-	UNSIGNED_BYTE_2_3_3_REV         = 0x8362
-	UNSIGNED_SHORT_5_6_5            = 0x8363
-	UNSIGNED_SHORT_5_6_5_REV        = 0x8364
-	UNSIGNED_SHORT_4_4_4_4_REV      = 0x8365
-	UNSIGNED_SHORT_1_5_5_5_REV      = 0x8366
-	UNSIGNED_INT_8_8_8_8_REV        = 0x8367
-	UNSIGNED_INT_2_10_10_10_REV     = 0x8368
+	COMPRESSED_RGB_S3TC_DXT1           = 0x83F0
+	COMPRESSED_RGBA_S3TC_DXT1          = 0x83F1
+	COMPRESSED_RGBA_S3TC_DXT3          = 0x83F2
+	COMPRESSED_RGBA_S3TC_DXT5          = 0x83F3
+	COMPRESSED_SRGB_S3TC_DXT1          = 0x8C4C
+	COMPRESSED_SRGB_ALPHA_S3TC_DXT1    = 0x8C4D
+	COMPRESSED_SRGB_ALPHA_S3TC_DXT3    = 0x8C4E
+	COMPRESSED_SRGB_ALPHA_S3TC_DXT5    = 0x8C4F
+	COMPRESSED_RED_RGTC1               = 0x8DBB
+	COMPRESSED_SIGNED_RED_RGTC1        = 0x8DBC
+	COMPRESSED_RG_RGTC2                = 0x8DBD
+	COMPRESSED_SIGNED_RG_RGTC2         = 0x8DBE
+	COMPRESSED_RGBA_BPTC_UNORM         = 0x8E8C
+	COMPRESSED_SRGB_ALPHA_BPTC_UNORM   = 0x8E8D
+	COMPRESSED_RGB_BPTC_SIGNED_FLOAT   = 0x8E8E
+	COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT = 0x8E8F
+	UNSIGNED_BYTE_3_3_2                = 0x8032
+	UNSIGNED_SHORT_4_4_4_4             = 0x8033
+	UNSIGNED_SHORT_5_5_5_1             = 0x8034
+	UNSIGNED_INT_8_8_8_8               = 0x8035
+	UNSIGNED_INT_10_10_10_2            = 0x8036
+	UNSIGNED_BYTE_2_3_3_REV            = 0x8362
+	UNSIGNED_SHORT_5_6_5               = 0x8363
+	UNSIGNED_SHORT_5_6_5_REV           = 0x8364
+	UNSIGNED_SHORT_4_4_4_4_REV         = 0x8365
+	UNSIGNED_SHORT_1_5_5_5_REV         = 0x8366
+	UNSIGNED_INT_8_8_8_8_REV           = 0x8367
+	UNSIGNED_INT_2_10_10_10_REV        = 0x8368
 )
 
-// Returns GL parameters for loading data from the subrect "r" of image "img"
-func getImageInfo(i image.Image) imageInfo {
+// getImageInfoKnown returns GL parameters for the image types gla has an
+// explicit, zero-copy mapping for. It reports false for any other concrete
+// type, leaving the caller to decide whether a conversion fallback (as in
+// getImageInfo) or an error (as in getImageInfoForRead) is appropriate.
+func getImageInfoKnown(i image.Image) (imageInfo, bool) {
 	var data reflect.Value
 	var stride int
 	var epp int // elements per pixel
@@ -154,14 +165,7 @@ func getImageInfo(i image.Image) imageInfo {
 		data, stride, epp = reflect.ValueOf(img.Pix), img.Stride, 1
 		info.Format, info.Type = gl.RGB, UNSIGNED_SHORT_5_6_5
 	default:
-		// for unknown types, convert to RGBA8
-		r := i.Bounds()
-		img := image.NewRGBA(r)
-		draw.Draw(img, r.Sub(r.Min), i, r.Min, draw.Src)
-		info.Format, info.Type = gl.RGBA, gl.UNSIGNED_BYTE
-		info.Data = unsafe.Pointer(reflect.ValueOf(img.Pix).Index(0).UnsafeAddr())
-		info.RowLength = img.Stride / 4
-		return info
+		return imageInfo{}, false
 	}
 
 	info.Data = unsafe.Pointer(data.Index(0).UnsafeAddr())
@@ -171,9 +175,46 @@ func getImageInfo(i image.Image) imageInfo {
 		panic("gla: stride isn't usable with OpenGL")
 	}
 
+	return info, true
+}
+
+// getImageInfo returns GL parameters for loading data from image "i". Types
+// getImageInfoKnown doesn't map directly (image.NRGBA, image.Paletted, a
+// user-defined image.Image, ...) are converted to a throwaway RGBA8 copy;
+// this fallback only makes sense for the upload path, where the source
+// pixels already exist and a copy merely costs time. Do not reuse it for
+// readback, where "convert and discard" would silently drop the result
+// instead of writing it into the caller's image - see getImageInfoForRead.
+func getImageInfo(i image.Image) imageInfo {
+	if info, ok := getImageInfoKnown(i); ok {
+		return info
+	}
+
+	// for unknown types, convert to RGBA8
+	r := i.Bounds()
+	img := image.NewRGBA(r)
+	draw.Draw(img, r.Sub(r.Min), i, r.Min, draw.Src)
+
+	var info imageInfo
+	info.Format, info.Type = gl.RGBA, gl.UNSIGNED_BYTE
+	info.Data = unsafe.Pointer(reflect.ValueOf(img.Pix).Index(0).UnsafeAddr())
+	info.RowLength = img.Stride / 4
 	return info
 }
 
+// getImageInfoForRead returns GL parameters for reading pixel data into the
+// subrect of image "i", erroring out on any concrete type getImageInfoKnown
+// doesn't explicitly support rather than falling back to the write-oriented
+// RGBA8 conversion in getImageInfo, which would read into a temporary image
+// that's discarded instead of "i".
+func getImageInfoForRead(i image.Image) (imageInfo, error) {
+	info, ok := getImageInfoKnown(i)
+	if !ok {
+		return imageInfo{}, fmt.Errorf("gla: unsupported image type %T for ReadPixelsToImage", i)
+	}
+	return info, nil
+}
+
 // TexImage2DFromImage loads texture data from an image.Image into the currently
 // bound GL texture using the glTexImage2D call. If you wish to load only part of
 // an image, pass a subimage as the argument.
@@ -221,6 +262,32 @@ func TexSubImage2DFromImage(target GLenum, level int, dest image.Rectangle, img
 		info.Data)
 }
 
+// CompressedImage is implemented by block-compressed image types that gla
+// doesn't have a dedicated case for in getCompressedImageInfo (e.g. the
+// RGTC/BPTC formats), so adding a new one doesn't require a corresponding
+// code change here.
+type CompressedImage interface {
+	image.Image
+	CompressedPix() []uint8
+	CompressedStride() int
+	BlockDim() int
+	BlockSize() int
+	CompressedFormat() GLenum
+}
+
+// isCompressedImage reports whether i is handled by getCompressedImageInfo,
+// i.e. whether it should be uploaded via CompressedTexImage2DFromImage
+// rather than TexImage2DFromImage. Kept in sync with that function's type
+// switch so callers don't have to duplicate it.
+func isCompressedImage(i image.Image) bool {
+	switch i.(type) {
+	case *glimage.Dxt1, *glimage.Dxt3, *glimage.Dxt5, CompressedImage:
+		return true
+	default:
+		return false
+	}
+}
+
 // Returns GL parameters for loading data from the subrect "r" of image "img"
 func getCompressedImageInfo(i image.Image) (imageInfo, error) {
 	var data []uint8
@@ -242,6 +309,11 @@ func getCompressedImageInfo(i image.Image) (imageInfo, error) {
 		img, _ := i.(*glimage.Dxt5)
 		data, stride, blockdim, blocksize = img.Pix, img.Stride, 4, 16
 		info.Format = COMPRESSED_RGBA_S3TC_DXT5
+	case CompressedImage:
+		img, _ := i.(CompressedImage)
+		data, stride = img.CompressedPix(), img.CompressedStride()
+		blockdim, blocksize = img.BlockDim(), img.BlockSize()
+		info.Format = img.CompressedFormat()
 	default:
 		return imageInfo{}, fmt.Errorf("gla: unrecognized texture format")
 	}
@@ -294,6 +366,220 @@ func CompressedTexImage2DFromImage(target GLenum, level int, border int, img ima
 		C.GLsizei(info.Length), info.Data)
 }
 
+// TexImage2DFromMipChain iterates mips, level 0 first, and uploads each
+// level into the currently bound texture, dispatching to
+// CompressedTexImage2DFromImage for images getCompressedImageInfo knows how
+// to handle (the S3TC types and CompressedImage, as returned by
+// LoadDDS/LoadKTX) and to TexImage2DFromImage with internalformat otherwise.
+//
+// Precondition: no buffer object bound to PIXEL_UNPACK_BUFFER
+func TexImage2DFromMipChain(target GLenum, internalformat int, mips []image.Image) {
+	for level, img := range mips {
+		if isCompressedImage(img) {
+			CompressedTexImage2DFromImage(target, level, 0, img)
+		} else {
+			TexImage2DFromImage(target, level, internalformat, 0, img)
+		}
+	}
+}
+
+// PixelFormat identifies one of the image.Image representations that
+// ReadPixelsNewImage and MapPixelsAsImage know how to allocate. It mirrors
+// the concrete types getImageInfo already maps to a GL format/type when
+// uploading.
+type PixelFormat int
+
+const (
+	FormatRGBA PixelFormat = iota
+	FormatGray
+	FormatBGRA
+	FormatBGR565
+)
+
+// The following are missing from github.com/banthar/gl
+const (
+	MAP_READ_BIT = 0x0001
+)
+
+type pixelFormatInfo struct {
+	Format GLenum
+	Type   GLenum
+}
+
+func pixelFormatGL(format PixelFormat) (pixelFormatInfo, error) {
+	switch format {
+	case FormatRGBA:
+		return pixelFormatInfo{gl.RGBA, gl.UNSIGNED_BYTE}, nil
+	case FormatGray:
+		return pixelFormatInfo{gl.LUMINANCE, gl.UNSIGNED_BYTE}, nil
+	case FormatBGRA:
+		return pixelFormatInfo{gl.BGRA, gl.UNSIGNED_BYTE}, nil
+	case FormatBGR565:
+		return pixelFormatInfo{gl.RGB, UNSIGNED_SHORT_5_6_5}, nil
+	default:
+		return pixelFormatInfo{}, fmt.Errorf("gla: unrecognized pixel format")
+	}
+}
+
+func bytesPerPixel(format PixelFormat) (int, error) {
+	switch format {
+	case FormatRGBA, FormatBGRA:
+		return 4, nil
+	case FormatGray:
+		return 1, nil
+	case FormatBGR565:
+		return 2, nil
+	default:
+		return 0, fmt.Errorf("gla: unrecognized pixel format")
+	}
+}
+
+func newImageForFormat(format PixelFormat, r image.Rectangle) (image.Image, error) {
+	switch format {
+	case FormatRGBA:
+		return image.NewRGBA(r), nil
+	case FormatGray:
+		return image.NewGray(r), nil
+	case FormatBGRA:
+		return glimage.NewBGRA(r), nil
+	case FormatBGR565:
+		return glimage.NewBGR565(r), nil
+	default:
+		return nil, fmt.Errorf("gla: unrecognized pixel format")
+	}
+}
+
+// sliceAtPointer builds a []uint8 aliasing length bytes starting at ptr,
+// without copying. The caller is responsible for keeping the backing memory
+// valid for as long as the slice is in use.
+func sliceAtPointer(ptr unsafe.Pointer, length int) []uint8 {
+	var s []uint8
+	h := (*reflect.SliceHeader)(unsafe.Pointer(&s))
+	h.Data = uintptr(ptr)
+	h.Len = length
+	h.Cap = length
+	return s
+}
+
+// ReadPixelsToImage uses glReadPixels to read back the subrect "rect" of the
+// currently bound framebuffer into img. The concrete type of img determines
+// the GL format/type passed to glReadPixels; see getImageInfoKnown for the
+// supported types. Unlike TexImage2DFromImage's upload path, an unsupported
+// concrete type is an error rather than a silent RGBA8 conversion, since
+// there's no caller-visible destination to convert into for a readback.
+//
+// Additional state modified: PACK_ALIGNMENT, PACK_ROW_LENGTH
+func ReadPixelsToImage(rect image.Rectangle, img image.Image) error {
+	bounds := img.Bounds()
+	if rect.Dx() > bounds.Dx() || rect.Dy() > bounds.Dy() {
+		return fmt.Errorf("gla: rect %v exceeds image bounds %v", rect, bounds)
+	}
+
+	info, err := getImageInfoForRead(img)
+	if err != nil {
+		return err
+	}
+
+	C.glPixelStorei(C.GLenum(gl.PACK_ALIGNMENT), C.GLint(1))
+	C.glPixelStorei(C.GLenum(gl.PACK_ROW_LENGTH), C.GLint(info.RowLength))
+	C.glReadPixels(C.GLint(rect.Min.X), C.GLint(rect.Min.Y),
+		C.GLsizei(rect.Dx()), C.GLsizei(rect.Dy()),
+		C.GLenum(info.Format), C.GLenum(info.Type),
+		info.Data)
+	return nil
+}
+
+// ReadPixelsNewImage allocates a new image of the given format sized to
+// rect, and fills it with the subrect "rect" of the currently bound
+// framebuffer via ReadPixelsToImage.
+//
+// Additional state modified: PACK_ALIGNMENT, PACK_ROW_LENGTH
+func ReadPixelsNewImage(rect image.Rectangle, format PixelFormat) (image.Image, error) {
+	img, err := newImageForFormat(format, rect)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ReadPixelsToImage(rect, img); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// ReadPixelsPBO issues an asynchronous read of the subrect "rect" of the
+// currently bound framebuffer into the buffer object bound to
+// PIXEL_PACK_BUFFER, starting at byte offset "offset". glReadPixels always
+// writes to the PIXEL_PACK_BUFFER binding; target is not used. Unlike
+// ReadPixelsToImage, this call returns as soon as the request is queued;
+// retrieve the data once the GPU has finished writing it with
+// MapPixelsAsImage.
+//
+// Precondition: buffer object bound to PIXEL_PACK_BUFFER, sized to hold the
+// pixel data via BufferData
+//
+// Additional state modified: PACK_ALIGNMENT, PACK_ROW_LENGTH
+func ReadPixelsPBO(rect image.Rectangle, target GLenum, format PixelFormat, offset int) error {
+	info, err := pixelFormatGL(format)
+	if err != nil {
+		return err
+	}
+
+	C.glPixelStorei(C.GLenum(gl.PACK_ALIGNMENT), C.GLint(1))
+	C.glPixelStorei(C.GLenum(gl.PACK_ROW_LENGTH), C.GLint(0))
+	C.glReadPixels(C.GLint(rect.Min.X), C.GLint(rect.Min.Y),
+		C.GLsizei(rect.Dx()), C.GLsizei(rect.Dy()),
+		C.GLenum(info.Format), C.GLenum(info.Type),
+		unsafe.Pointer(uintptr(offset)))
+	return nil
+}
+
+// UnmapFunc unmaps the buffer object that was mapped to produce the image
+// returned alongside it. Calling it invalidates that image; don't touch its
+// pixel data afterwards.
+type UnmapFunc func()
+
+// MapPixelsAsImage uses glMapBufferRange to map the buffer object bound to
+// target (normally PIXEL_PACK_BUFFER), starting at byte offset "offset", and
+// wraps the mapped range as an image.Image of the given format sized to
+// rect, without copying. Call the returned UnmapFunc once you're done
+// reading the image to release the mapping.
+func MapPixelsAsImage(target GLenum, offset int, rect image.Rectangle, format PixelFormat) (image.Image, UnmapFunc, error) {
+	bpp, err := bytesPerPixel(format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stride := rect.Dx() * bpp
+	length := stride * rect.Dy()
+
+	ptr := C.glMapBufferRange(C.GLenum(target), C.GLintptr(offset), C.GLsizeiptr(length), C.GLbitfield(MAP_READ_BIT))
+	if ptr == nil {
+		return nil, nil, fmt.Errorf("gla: glMapBufferRange returned NULL")
+	}
+
+	pix := sliceAtPointer(ptr, length)
+
+	var img image.Image
+	switch format {
+	case FormatRGBA:
+		img = &image.RGBA{Pix: pix, Stride: stride, Rect: rect}
+	case FormatGray:
+		img = &image.Gray{Pix: pix, Stride: stride, Rect: rect}
+	case FormatBGRA:
+		img = &glimage.BGRA{Pix: pix, Stride: stride, Rect: rect}
+	case FormatBGR565:
+		img = &glimage.BGR565{Pix: pix, Stride: stride, Rect: rect}
+	default:
+		return nil, nil, fmt.Errorf("gla: unrecognized pixel format")
+	}
+
+	unmap := UnmapFunc(func() {
+		C.glUnmapBuffer(C.GLenum(target))
+	})
+
+	return img, unmap, nil
+}
+
 //
 // Buffer Functions
 //
@@ -368,6 +654,58 @@ func BufferSubData(target GLenum, start_index int, slice interface{}) error {
 	return nil
 }
 
+// sliceFromPointer builds a slice whose elements have dummy's type, of length
+// byteLen/sizeof(dummy), aliasing byteLen bytes of memory starting at ptr.
+// It doesn't copy; the returned value is only valid while that memory is.
+func sliceFromPointer(ptr unsafe.Pointer, byteLen int, dummy interface{}) interface{} {
+	elemType := reflect.TypeOf(dummy)
+	length := byteLen / int(elemType.Size())
+
+	header := reflect.SliceHeader{Data: uintptr(ptr), Len: length, Cap: length}
+	return reflect.NewAt(reflect.SliceOf(elemType), unsafe.Pointer(&header)).Elem().Interface()
+}
+
+// MapBuffer uses glMapBuffer to map the entirety of the buffer object bound
+// to target into client memory, and returns it as a slice whose elements
+// have dummy's type ([]T for a dummy of type T), aliasing the mapped GPU
+// memory directly, the same way VertexAttribSlice
+// infers layout from a zero value of the slice's element type.
+//
+// The returned slice is only valid between this call and the matching
+// UnmapBuffer; don't hold on to it any longer than that, and don't use it
+// after UnmapBuffer is called.
+func MapBuffer(target GLenum, access GLbitfield, dummy interface{}) (slice interface{}, err error) {
+	var size C.GLint
+	C.glGetBufferParameteriv(C.GLenum(target), C.GLenum(gl.BUFFER_SIZE), &size)
+
+	ptr := C.glMapBuffer(C.GLenum(target), C.GLenum(access))
+	if ptr == nil {
+		return nil, fmt.Errorf("gla: glMapBuffer returned NULL")
+	}
+
+	return sliceFromPointer(ptr, int(size), dummy), nil
+}
+
+// MapBufferRange uses glMapBufferRange to map the subrange
+// [offset, offset+length) (in bytes) of the buffer object bound to target,
+// and returns it as a slice whose elements have dummy's type. See MapBuffer for the
+// validity rules governing the returned slice.
+func MapBufferRange(target GLenum, offset, length int, access GLbitfield, dummy interface{}) (slice interface{}, err error) {
+	ptr := C.glMapBufferRange(C.GLenum(target), C.GLintptr(offset), C.GLsizeiptr(length), C.GLbitfield(access))
+	if ptr == nil {
+		return nil, fmt.Errorf("gla: glMapBufferRange returned NULL")
+	}
+
+	return sliceFromPointer(ptr, length, dummy), nil
+}
+
+// UnmapBuffer uses glUnmapBuffer to release the mapping obtained from
+// MapBuffer or MapBufferRange for the buffer object bound to target. Any
+// slice previously returned for that buffer is invalid after this call.
+func UnmapBuffer(target GLenum) {
+	C.glUnmapBuffer(C.GLenum(target))
+}
+
 //
 // Vertex Attrib Functions
 //
@@ -0,0 +1,236 @@
+// Copyright (c) 2012, James Helferty. All rights reserved.
+// Use of this source code is governed by a Clear BSD License
+// that can be found in the LICENSE file.
+
+package gla
+
+// #cgo darwin LDFLAGS: -framework OpenGL
+// #cgo darwin pkg-config: glew
+// #cgo windows LDFLAGS: -lglew32 -lopengl32
+// #cgo linux LDFLAGS: -lGLEW -lGL
+//
+// #include <stdlib.h>
+//
+// #ifdef __APPLE__
+// # include "glew.h"
+// #else
+// # include <GL/glew.h>
+// #endif
+//
+// #undef GLEW_GET_FUN
+// #define GLEW_GET_FUN(x) (*x)
+import "C"
+import "fmt"
+import "reflect"
+import "sync"
+import "unsafe"
+import "github.com/banthar/gl"
+
+type uniformFieldInfo struct {
+	FieldIndex int
+	Location   C.GLint
+}
+
+type uniformCacheKey struct {
+	Type    reflect.Type
+	Program C.GLuint
+}
+
+var uniformCacheMu sync.Mutex
+var uniformCache = make(map[uniformCacheKey][]uniformFieldInfo)
+
+// uniformFieldName returns the uniform name a struct field maps to: the
+// `gla:"..."` tag if present, otherwise the field's own name.
+func uniformFieldName(sf reflect.StructField) string {
+	if tag := sf.Tag.Get("gla"); tag != "" {
+		return tag
+	}
+	return sf.Name
+}
+
+// uniformFieldsFor resolves and caches, per (struct type, program), the
+// glGetUniformLocation result for every exported field of t.
+func uniformFieldsFor(program C.GLuint, t reflect.Type) []uniformFieldInfo {
+	key := uniformCacheKey{Type: t, Program: program}
+
+	uniformCacheMu.Lock()
+	defer uniformCacheMu.Unlock()
+
+	if fields, ok := uniformCache[key]; ok {
+		return fields
+	}
+
+	fields := make([]uniformFieldInfo, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue // unexported field
+		}
+
+		cname := C.CString(uniformFieldName(sf))
+		loc := C.glGetUniformLocation(program, (*C.GLchar)(unsafe.Pointer(cname)))
+		C.free(unsafe.Pointer(cname))
+
+		fields = append(fields, uniformFieldInfo{FieldIndex: i, Location: loc})
+	}
+
+	uniformCache[key] = fields
+	return fields
+}
+
+// uniformDataPtr returns a pointer to v's backing data: the first element's
+// address for a slice, v's own address if it's addressable, or the address
+// of a freshly made copy otherwise.
+func uniformDataPtr(v reflect.Value) unsafe.Pointer {
+	if v.Kind() == reflect.Slice {
+		return unsafe.Pointer(v.Index(0).UnsafeAddr())
+	}
+	if v.CanAddr() {
+		return unsafe.Pointer(v.UnsafeAddr())
+	}
+
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return unsafe.Pointer(p.Pointer())
+}
+
+// setUniformValue dispatches to the glUniform{1,2,3,4}{f,i,ui}v or
+// glUniformMatrix{2,3,4}fv call matching v's type, uploading it to location.
+// v may be a scalar, a [N]T array (N=2..4) for a vecN/ivecN/uvecN, a
+// [N][N]float32 or flat [N*N]float32 array for a matN, or a slice of any of
+// those for a uniform array. [4]float32 is taken to mean vec4 rather than
+// the flat form of mat2, since the two are structurally indistinguishable;
+// use [2][2]float32 for mat2.
+func setUniformValue(location C.GLint, v reflect.Value) error {
+	t := v.Type()
+	count := 1
+	elemType := t
+
+	if t.Kind() == reflect.Slice {
+		if v.Len() == 0 {
+			return nil
+		}
+		count, elemType = v.Len(), t.Elem()
+	}
+
+	ptr := uniformDataPtr(v)
+
+	switch elemType.Kind() {
+	case reflect.Float32:
+		C.glUniform1fv(location, C.GLsizei(count), (*C.GLfloat)(ptr))
+	case reflect.Int32:
+		C.glUniform1iv(location, C.GLsizei(count), (*C.GLint)(ptr))
+	case reflect.Uint32:
+		C.glUniform1uiv(location, C.GLsizei(count), (*C.GLuint)(ptr))
+
+	case reflect.Array:
+		n, inner := elemType.Len(), elemType.Elem()
+
+		if inner.Kind() == reflect.Array {
+			if inner.Elem().Kind() != reflect.Float32 || inner.Len() != n {
+				return fmt.Errorf("gla: unsupported uniform matrix type")
+			}
+			switch n {
+			case 2:
+				C.glUniformMatrix2fv(location, C.GLsizei(count), glBool(false), (*C.GLfloat)(ptr))
+			case 3:
+				C.glUniformMatrix3fv(location, C.GLsizei(count), glBool(false), (*C.GLfloat)(ptr))
+			case 4:
+				C.glUniformMatrix4fv(location, C.GLsizei(count), glBool(false), (*C.GLfloat)(ptr))
+			default:
+				return fmt.Errorf("gla: unsupported uniform matrix dimension")
+			}
+			return nil
+		}
+
+		switch inner.Kind() {
+		case reflect.Float32:
+			switch n {
+			case 2:
+				C.glUniform2fv(location, C.GLsizei(count), (*C.GLfloat)(ptr))
+			case 3:
+				C.glUniform3fv(location, C.GLsizei(count), (*C.GLfloat)(ptr))
+			case 4:
+				C.glUniform4fv(location, C.GLsizei(count), (*C.GLfloat)(ptr))
+			case 9:
+				C.glUniformMatrix3fv(location, C.GLsizei(count), glBool(false), (*C.GLfloat)(ptr))
+			case 16:
+				C.glUniformMatrix4fv(location, C.GLsizei(count), glBool(false), (*C.GLfloat)(ptr))
+			default:
+				return fmt.Errorf("gla: unsupported uniform vector size")
+			}
+		case reflect.Int32:
+			switch n {
+			case 2:
+				C.glUniform2iv(location, C.GLsizei(count), (*C.GLint)(ptr))
+			case 3:
+				C.glUniform3iv(location, C.GLsizei(count), (*C.GLint)(ptr))
+			case 4:
+				C.glUniform4iv(location, C.GLsizei(count), (*C.GLint)(ptr))
+			default:
+				return fmt.Errorf("gla: unsupported uniform vector size")
+			}
+		case reflect.Uint32:
+			switch n {
+			case 2:
+				C.glUniform2uiv(location, C.GLsizei(count), (*C.GLuint)(ptr))
+			case 3:
+				C.glUniform3uiv(location, C.GLsizei(count), (*C.GLuint)(ptr))
+			case 4:
+				C.glUniform4uiv(location, C.GLsizei(count), (*C.GLuint)(ptr))
+			default:
+				return fmt.Errorf("gla: unsupported uniform vector size")
+			}
+		default:
+			return fmt.Errorf("gla: unsupported uniform array element type")
+		}
+
+	default:
+		return fmt.Errorf("gla: unsupported uniform field type")
+	}
+
+	return nil
+}
+
+// SetUniforms walks data (a struct, or pointer to one) and uploads one
+// uniform per exported field to program, resolving each field's location
+// from a `gla:"name"` struct tag or, absent one, the field's own name.
+// Locations are resolved once per (struct type, program) and cached, so
+// repeated calls against the same program don't re-query
+// glGetUniformLocation.
+//
+// Supported field types: float32/int32/uint32 scalars; [N]T (N=2..4) for
+// vecN/ivecN/uvecN; [N][N]float32 (N=2..4) or flat [N*N]float32 (N=3..4) for
+// matN; and slices of any of those for uniform arrays. Flat mat2 isn't
+// supported (a flat [4]float32 is read as vec4); use [2][2]float32 instead.
+func SetUniforms(program gl.Program, data interface{}) error {
+	v := reflect.ValueOf(data)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("gla: SetUniforms needs a struct or a pointer to one")
+	}
+
+	for _, f := range uniformFieldsFor(C.GLuint(program), v.Type()) {
+		if f.Location < 0 {
+			continue // not an active uniform; the GL spec allows silently ignoring this
+		}
+		if err := setUniformValue(f.Location, v.Field(f.FieldIndex)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetUniformStruct uploads data (a value, or pointer to one) to location
+// directly, using the same type dispatch as SetUniforms. Use it when you
+// already have a uniform's location (e.g. from glGetUniformLocation)
+// instead of a whole struct of uniforms to push at once.
+func SetUniformStruct(location gl.UniformLocation, data interface{}) error {
+	v := reflect.ValueOf(data)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	return setUniformValue(C.GLint(location), v)
+}